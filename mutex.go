@@ -0,0 +1,17 @@
+//go:build go1.8
+// +build go1.8
+
+package profile
+
+import "runtime"
+
+// enableMutexProfile turns on mutex profiling at the given fraction.
+// See also runtime.SetMutexProfileFraction.
+func enableMutexProfile(fraction int) {
+	runtime.SetMutexProfileFraction(fraction)
+}
+
+// disableMutexProfile turns mutex profiling back off.
+func disableMutexProfile() {
+	runtime.SetMutexProfileFraction(0)
+}
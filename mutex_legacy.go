@@ -0,0 +1,11 @@
+//go:build !go1.8
+// +build !go1.8
+
+package profile
+
+// enableMutexProfile is a no-op before go1.8, where mutex profiling
+// does not exist.
+func enableMutexProfile(fraction int) {}
+
+// disableMutexProfile is a no-op before go1.8.
+func disableMutexProfile() {}
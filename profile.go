@@ -3,6 +3,8 @@
 package profile
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
@@ -11,12 +13,15 @@ import (
 	"runtime"
 	"runtime/pprof"
 	"sync/atomic"
+	"time"
 )
 
 // Profile represents an active profiling session.
 type Profile struct {
-	// quiet suppresses informational messages during profiling.
-	quiet bool
+	// logger receives profiling's informational messages (e.g.
+	// "profiling enabled"). It defaults to log.Printf; Quiet installs
+	// a no-op logger through the same mechanism as WithLogger.
+	logger func(format string, args ...interface{})
 
 	// noShutdownHook controls whether the profiling package should
 	// hook SIGINT to write profiles cleanly.
@@ -32,6 +37,12 @@ type Profile struct {
 	blockMode bool
 	// traceMode make trace out
 	traceMode bool
+	// goroutineMode holds the goroutine type of profiling
+	goroutineMode bool
+	// threadcreateMode holds the threadcreate type of profiling
+	threadcreateMode bool
+	// allocsMode holds the allocs type of profiling
+	allocsMode bool
 
 	// path holds the base path where various profiling files are  written.
 	// If blank, the base path will be generated by ioutil.TempDir.
@@ -40,11 +51,39 @@ type Profile struct {
 	// memProfileRate holds the rate for the memory profile.
 	memProfileRate int
 
+	// mutexProfileFraction holds the fraction for the mutex profile.
+	// See also runtime.SetMutexProfileFraction.
+	mutexProfileFraction int
+
+	// blockProfileRate holds the rate for the block profile.
+	// See also runtime.SetBlockProfileRate.
+	blockProfileRate int
+
 	// closers holds a cleanup functions that run after each profile
 	closers []func()
 
 	// stopped records if a call to profile.Stop has been made
 	stopped uint32
+
+	// stopSignal holds an additional OS signal that stops profiling
+	// without terminating the process, so a new session can be started.
+	stopSignal os.Signal
+
+	// duration, if non-zero, stops profiling automatically once it
+	// has elapsed since Start was called.
+	duration time.Duration
+
+	// sink is where each finished profile is written. If nil, Start
+	// installs the default filesystem sink rooted at path.
+	sink Sink
+
+	// gzip wraps sink in a gzipSink once it has been resolved.
+	gzip bool
+
+	// done is closed exactly once, by Stop, so goroutines spawned by
+	// Start (the interrupt catcher, the duration timer) can unblock
+	// and exit instead of leaking past the end of the session.
+	done chan struct{}
 }
 
 // NoShutdownHook controls whether the profiling package should
@@ -54,8 +93,19 @@ type Profile struct {
 // is called during shutdown.
 func NoShutdownHook(p *Profile) { p.noShutdownHook = true }
 
-// Quiet suppresses informational messages during profiling.
-func Quiet(p *Profile) { p.quiet = true }
+// Quiet suppresses informational messages during profiling by
+// installing a no-op logger, via the same mechanism as WithLogger.
+func Quiet(p *Profile) { p.logger = func(string, ...interface{}) {} }
+
+// WithLogger routes profiling's informational messages (e.g.
+// "profiling enabled") through logger instead of the standard library's
+// log package, so applications using zap/zerolog/slog can route them
+// through their own pipeline.
+func WithLogger(logger func(format string, args ...interface{})) func(*Profile) {
+	return func(p *Profile) {
+		p.logger = logger
+	}
+}
 
 // CPUProfile enables cpu profiling.
 // It NOT disables any previous profiling settings (multi profiling supported).
@@ -81,28 +131,95 @@ func MemProfileRate(rate int) func(*Profile) {
 	}
 }
 
+// DefaultMutexProfileFraction is the default mutex profiling fraction,
+// matching the rate profiling previously ran at unconditionally.
+// See also runtime.SetMutexProfileFraction.
+const DefaultMutexProfileFraction = 1
+
 // MutexProfile enables mutex profiling.
 // It NOT disables any previous profiling settings (multi profiling supported).
 //
 // Mutex profiling is a no-op before go1.8.
-func MutexProfile(p *Profile) { p.mutexMode = true }
+func MutexProfile(p *Profile) {
+	p.mutexProfileFraction = DefaultMutexProfileFraction
+	p.mutexMode = true
+}
+
+// MutexProfileFraction enables mutex profiling at the preferred fraction.
+// It NOT disables any previous profiling settings (multi profiling supported).
+//
+// A fraction of 0 disables mutex profiling (see runtime.SetMutexProfileFraction),
+// so it leaves mutexMode unset rather than writing an always-empty mutex.pprof.
+//
+// Mutex profiling is a no-op before go1.8.
+func MutexProfileFraction(fraction int) func(*Profile) {
+	return func(p *Profile) {
+		p.mutexProfileFraction = fraction
+		p.mutexMode = fraction != 0
+	}
+}
+
+// DefaultBlockProfileRate is the default block profiling rate, matching
+// the rate profiling previously ran at unconditionally. It is expensive
+// in production; prefer BlockProfileRate with a larger value there.
+// See also runtime.SetBlockProfileRate.
+const DefaultBlockProfileRate = 1
 
 // BlockProfile enables block (contention) profiling.
 // It NOT disables any previous profiling settings (multi profiling supported).
-func BlockProfile(p *Profile) { p.blockMode = true }
+func BlockProfile(p *Profile) {
+	p.blockProfileRate = DefaultBlockProfileRate
+	p.blockMode = true
+}
+
+// BlockProfileRate enables block (contention) profiling at the preferred
+// rate. It NOT disables any previous profiling settings (multi profiling
+// supported).
+//
+// A rate of 0 disables block profiling (see runtime.SetBlockProfileRate),
+// so it leaves blockMode unset rather than writing an always-empty
+// block.pprof.
+func BlockProfileRate(rate int) func(*Profile) {
+	return func(p *Profile) {
+		p.blockProfileRate = rate
+		p.blockMode = rate != 0
+	}
+}
 
 // TraceProfile profile controls if execution tracing will be enabled.
 // It NOT disables any previous profiling settings (multi profiling supported).
 func TraceProfile(p *Profile) { p.traceMode = true }
 
-// ProfileAll set to enables CPUProfile, MemProfile, MutexProfile, BlockProfile and TraceProfile.
+// GoroutineProfile controls if a snapshot of all current goroutine stacks
+// will be written. It NOT disables any previous profiling settings
+// (multi profiling supported).
+func GoroutineProfile(p *Profile) { p.goroutineMode = true }
+
+// ThreadcreationProfile controls if a snapshot of all past OS thread
+// creation stacks will be written. It NOT disables any previous profiling
+// settings (multi profiling supported).
+func ThreadcreationProfile(p *Profile) { p.threadcreateMode = true }
+
+// AllocsProfile controls if a snapshot of all past memory allocations
+// will be written. It NOT disables any previous profiling settings
+// (multi profiling supported).
+func AllocsProfile(p *Profile) { p.allocsMode = true }
+
+// ProfileAll set to enables CPUProfile, MemProfile, MutexProfile, BlockProfile,
+// TraceProfile, GoroutineProfile, ThreadcreationProfile and AllocsProfile.
 // Multi profiling supported.
 func ProfileAll(p *Profile) {
 	p.cpuMode = true
 	p.memMode = true
+	p.memProfileRate = DefaultMemProfileRate
 	p.mutexMode = true
+	p.mutexProfileFraction = DefaultMutexProfileFraction
 	p.blockMode = true
+	p.blockProfileRate = DefaultBlockProfileRate
 	p.traceMode = true
+	p.goroutineMode = true
+	p.threadcreateMode = true
+	p.allocsMode = true
 }
 
 // ProfilePath controls the base path where various profiling
@@ -120,6 +237,39 @@ func ProfilePathLocalDir(p *Profile) {
 	p.path, _ = ioutil.TempDir(localDir(), "profile")
 }
 
+// WithSink installs sink as the destination each finished profile is
+// written to, in place of the default filesystem layout rooted at
+// ProfilePath. See Sink for the interface profiles are written through.
+func WithSink(sink Sink) func(*Profile) {
+	return func(p *Profile) {
+		p.sink = sink
+	}
+}
+
+// GzipOutput wraps whichever sink is in effect (the default filesystem
+// sink, or one installed via WithSink) so every profile is gzip
+// compressed as it is written, with a ".gz" suffix added to its name.
+func GzipOutput(p *Profile) { p.gzip = true }
+
+// StopSignal sets an OS signal (e.g. syscall.SIGUSR1 on Unix) that stops
+// the profile early without terminating the process, unlike the built-in
+// SIGINT handler. This lets a caller profile a specific window of activity
+// and then keep serving traffic, optionally calling Restart to begin a new
+// session with fresh options.
+func StopSignal(sig os.Signal) func(*Profile) {
+	return func(p *Profile) {
+		p.stopSignal = sig
+	}
+}
+
+// Duration stops profiling automatically once d has elapsed since Start
+// was called, flushing whatever has been captured up to that point.
+func Duration(d time.Duration) func(*Profile) {
+	return func(p *Profile) {
+		p.duration = d
+	}
+}
+
 // Stop stops the profile and flushes any unwritten data.
 func (p *Profile) Stop() {
 	if !atomic.CompareAndSwapUint32(&p.stopped, 0, 1) {
@@ -131,6 +281,7 @@ func (p *Profile) Stop() {
 		closer()
 	}
 	atomic.StoreUint32(&started, 0)
+	close(p.done)
 }
 
 // started is non zero if a profile is running.
@@ -138,141 +289,303 @@ var started uint32
 
 // Start starts a new profiling session.
 // The caller should call the Stop method on the value returned
-// to cleanly stop profiling.
-func Start(options ...func(*Profile)) interface {
+// to cleanly stop profiling. Start never terminates the host program on
+// a setup failure, it returns the error instead; callers that want the
+// previous fatal-on-error behavior can use MustStart instead. Unless
+// NoShutdownHook is set, the SIGINT handler it installs still stops the
+// profiles and exits the process.
+func Start(options ...func(*Profile)) (interface {
 	Stop()
-} {
+}, error) {
 	if !atomic.CompareAndSwapUint32(&started, 0, 1) {
-		log.Fatal("profile: Start() already called")
+		return nil, errors.New("profile: Start() already called")
 	}
 
 	var prof Profile
+	prof.done = make(chan struct{})
+	prof.logger = log.Printf
 	for _, option := range options {
 		option(&prof)
 	}
-	if !prof.cpuMode && !prof.memMode && !prof.mutexMode && !prof.blockMode && !prof.traceMode {
+	if !prof.cpuMode && !prof.memMode && !prof.mutexMode && !prof.blockMode && !prof.traceMode &&
+		!prof.goroutineMode && !prof.threadcreateMode && !prof.allocsMode {
 		ProfileAll(&prof) // Default
 	}
 
-	path, err := func() (string, error) {
-		if p := prof.path; p != "" {
-			return p, os.MkdirAll(p, 0777)
+	// fail unwinds whatever profiles were already started, releases
+	// the started flag, and reports err instead of exiting the process.
+	fail := func(err error) (interface{ Stop() }, error) {
+		for _, closer := range prof.closers {
+			closer()
 		}
-		return ioutil.TempDir("", "profile")
-	}()
-
-	if err != nil {
-		log.Fatalf("profile: could not create initial output directory: %v", err)
+		atomic.StoreUint32(&started, 0)
+		return nil, err
 	}
 
-	logf := func(format string, args ...interface{}) {
-		if !prof.quiet {
-			log.Printf(format, args...)
+	if prof.sink == nil {
+		path, err := func() (string, error) {
+			if p := prof.path; p != "" {
+				return p, os.MkdirAll(p, 0777)
+			}
+			return ioutil.TempDir("", "profile")
+		}()
+
+		if err != nil {
+			return fail(fmt.Errorf("profile: could not create initial output directory: %v", err))
 		}
+		prof.sink = &fsSink{path: path}
+	}
+	if prof.gzip {
+		prof.sink = &gzipSink{next: prof.sink}
 	}
 
+	logf := prof.logger
+
 	if prof.cpuMode {
-		fn := filepath.Join(path, "cpu.pprof")
-		f, err := os.Create(fn)
+		const name = "cpu.pprof"
+		w, err := prof.sink.Create(name)
 		if err != nil {
-			log.Fatalf("profile: could not create cpu profile %q: %v", fn, err)
+			return fail(fmt.Errorf("profile: could not create cpu profile %q: %v", name, err))
 		}
-		logf("profile: cpu profiling enabled, %s", fn)
-		pprof.StartCPUProfile(f)
+		logf("profile: cpu profiling enabled, %s", name)
+		pprof.StartCPUProfile(w)
 		prof.closers = append(prof.closers,
 			func() {
 				pprof.StopCPUProfile()
-				f.Close()
-				logf("profile: cpu profiling disabled, %s", fn)
+				if err := w.Close(); err != nil {
+					logf("profile: cpu profiling disabled, %s, but failed to finalize: %v", name, err)
+					return
+				}
+				logf("profile: cpu profiling disabled, %s", name)
 			})
 	}
 
 	if prof.memMode {
-		fn := filepath.Join(path, "mem.pprof")
-		f, err := os.Create(fn)
+		const name = "mem.pprof"
+		w, err := prof.sink.Create(name)
 		if err != nil {
-			log.Fatalf("profile: could not create memory profile %q: %v", fn, err)
+			return fail(fmt.Errorf("profile: could not create memory profile %q: %v", name, err))
 		}
 		old := runtime.MemProfileRate
 		runtime.MemProfileRate = prof.memProfileRate
-		logf("profile: memory profiling enabled (rate %d), %s", runtime.MemProfileRate, fn)
+		logf("profile: memory profiling enabled (rate %d), %s", runtime.MemProfileRate, name)
 		prof.closers = append(prof.closers,
 			func() {
-				pprof.Lookup("heap").WriteTo(f, 0)
-				f.Close()
+				pprof.Lookup("heap").WriteTo(w, 0)
+				err := w.Close()
 				runtime.MemProfileRate = old
-				logf("profile: memory profiling disabled, %s", fn)
+				if err != nil {
+					logf("profile: memory profiling disabled, %s, but failed to finalize: %v", name, err)
+					return
+				}
+				logf("profile: memory profiling disabled, %s", name)
 			})
 	}
 
 	if prof.mutexMode {
-		fn := filepath.Join(path, "mutex.pprof")
-		f, err := os.Create(fn)
+		const name = "mutex.pprof"
+		w, err := prof.sink.Create(name)
 		if err != nil {
-			log.Fatalf("profile: could not create mutex profile %q: %v", fn, err)
+			return fail(fmt.Errorf("profile: could not create mutex profile %q: %v", name, err))
 		}
-		enableMutexProfile()
-		logf("profile: mutex profiling enabled, %s", fn)
+		enableMutexProfile(prof.mutexProfileFraction)
+		logf("profile: mutex profiling enabled (fraction %d), %s", prof.mutexProfileFraction, name)
 		prof.closers = append(prof.closers,
 			func() {
 				if mp := pprof.Lookup("mutex"); mp != nil {
-					mp.WriteTo(f, 0)
+					mp.WriteTo(w, 0)
 				}
-				f.Close()
+				err := w.Close()
 				disableMutexProfile()
-				logf("profile: mutex profiling disabled, %s", fn)
+				if err != nil {
+					logf("profile: mutex profiling disabled, %s, but failed to finalize: %v", name, err)
+					return
+				}
+				logf("profile: mutex profiling disabled, %s", name)
 			})
 	}
 
 	if prof.blockMode {
-		fn := filepath.Join(path, "block.pprof")
-		f, err := os.Create(fn)
+		const name = "block.pprof"
+		w, err := prof.sink.Create(name)
 		if err != nil {
-			log.Fatalf("profile: could not create block profile %q: %v", fn, err)
+			return fail(fmt.Errorf("profile: could not create block profile %q: %v", name, err))
 		}
-		runtime.SetBlockProfileRate(1)
-		logf("profile: block profiling enabled, %s", fn)
+		runtime.SetBlockProfileRate(prof.blockProfileRate)
+		logf("profile: block profiling enabled (rate %d), %s", prof.blockProfileRate, name)
 		prof.closers = append(prof.closers,
 			func() {
-				pprof.Lookup("block").WriteTo(f, 0)
-				f.Close()
+				pprof.Lookup("block").WriteTo(w, 0)
+				err := w.Close()
 				runtime.SetBlockProfileRate(0)
-				logf("profile: block profiling disabled, %s", fn)
+				if err != nil {
+					logf("profile: block profiling disabled, %s, but failed to finalize: %v", name, err)
+					return
+				}
+				logf("profile: block profiling disabled, %s", name)
 			})
 	}
 
 	if prof.traceMode {
-		fn := filepath.Join(path, "trace.out")
-		f, err := os.Create(fn)
+		const name = "trace.out"
+		w, err := prof.sink.Create(name)
 		if err != nil {
-			log.Fatalf("profile: could not create trace output file %q: %v", fn, err)
+			return fail(fmt.Errorf("profile: could not create trace output file %q: %v", name, err))
 		}
-		if err := startTrace(f); err != nil {
-			log.Fatalf("profile: could not start trace: %v", err)
+		if err := startTrace(w); err != nil {
+			return fail(fmt.Errorf("profile: could not start trace: %v", err))
 		}
-		logf("profile: trace enabled, %s", fn)
+		logf("profile: trace enabled, %s", name)
 		prof.closers = append(prof.closers,
 			func() {
 				stopTrace()
-				logf("profile: trace disabled, %s", fn)
+				if err := w.Close(); err != nil {
+					logf("profile: trace disabled, %s, but failed to finalize: %v", name, err)
+					return
+				}
+				logf("profile: trace disabled, %s", name)
+			})
+	}
+
+	if prof.goroutineMode {
+		const name = "goroutine.pprof"
+		w, err := prof.sink.Create(name)
+		if err != nil {
+			return fail(fmt.Errorf("profile: could not create goroutine profile %q: %v", name, err))
+		}
+		logf("profile: goroutine profiling enabled, %s", name)
+		prof.closers = append(prof.closers,
+			func() {
+				if gp := pprof.Lookup("goroutine"); gp != nil {
+					gp.WriteTo(w, 0)
+				}
+				if err := w.Close(); err != nil {
+					logf("profile: goroutine profiling disabled, %s, but failed to finalize: %v", name, err)
+					return
+				}
+				logf("profile: goroutine profiling disabled, %s", name)
+			})
+	}
+
+	if prof.threadcreateMode {
+		const name = "threadcreate.pprof"
+		w, err := prof.sink.Create(name)
+		if err != nil {
+			return fail(fmt.Errorf("profile: could not create threadcreate profile %q: %v", name, err))
+		}
+		logf("profile: threadcreate profiling enabled, %s", name)
+		prof.closers = append(prof.closers,
+			func() {
+				if tp := pprof.Lookup("threadcreate"); tp != nil {
+					tp.WriteTo(w, 0)
+				}
+				if err := w.Close(); err != nil {
+					logf("profile: threadcreate profiling disabled, %s, but failed to finalize: %v", name, err)
+					return
+				}
+				logf("profile: threadcreate profiling disabled, %s", name)
+			})
+	}
+
+	if prof.allocsMode {
+		const name = "allocs.pprof"
+		w, err := prof.sink.Create(name)
+		if err != nil {
+			return fail(fmt.Errorf("profile: could not create allocs profile %q: %v", name, err))
+		}
+		logf("profile: allocs profiling enabled, %s", name)
+		prof.closers = append(prof.closers,
+			func() {
+				if ap := pprof.Lookup("allocs"); ap != nil {
+					ap.WriteTo(w, 0)
+				}
+				if err := w.Close(); err != nil {
+					logf("profile: allocs profiling disabled, %s, but failed to finalize: %v", name, err)
+					return
+				}
+				logf("profile: allocs profiling disabled, %s", name)
 			})
 	}
 
 	if !prof.noShutdownHook {
 		go func() {
-			log.Println("profile: set interrupt catcher")
+			logf("profile: set interrupt catcher")
 			c := make(chan os.Signal, 1)
 			signal.Notify(c, os.Interrupt)
-			<-c
+			defer signal.Stop(c)
+
+			select {
+			case <-c:
+				logf("profile: caught interrupt, stopping profiles")
+				prof.Stop()
+				os.Exit(0)
+			case <-prof.done:
+				// Stop was already called elsewhere (e.g. the duration
+				// timer or the stop-signal watcher below); nothing left
+				// to do but exit cleanly.
+			}
+		}()
+	}
 
-			log.Println("profile: caught interrupt, stopping profiles")
-			prof.Stop()
+	// The stop-signal watcher is independent of noShutdownHook: it never
+	// exits the process, so it is exactly the kind of signal handling a
+	// caller with "more sophisticated signal handling" (see
+	// NoShutdownHook) would still want running.
+	if prof.stopSignal != nil {
+		go func() {
+			stop := make(chan os.Signal, 1)
+			signal.Notify(stop, prof.stopSignal)
+			defer signal.Stop(stop)
+
+			select {
+			case <-stop:
+				logf("profile: caught stop signal, stopping profiles")
+				prof.Stop()
+			case <-prof.done:
+			}
+		}()
+	}
 
-			os.Exit(0)
+	if prof.duration > 0 {
+		go func() {
+			t := time.NewTimer(prof.duration)
+			defer t.Stop()
+
+			select {
+			case <-t.C:
+				logf("profile: duration elapsed, stopping profiles")
+				prof.Stop()
+			case <-prof.done:
+			}
 		}()
 	}
 
-	return &prof
+	return &prof, nil
+}
+
+// MustStart is a shim for callers upgrading from Start's previous
+// signature, which terminated the host program on any setup failure.
+// It behaves exactly like Start but calls log.Fatal instead of
+// returning an error.
+func MustStart(options ...func(*Profile)) interface {
+	Stop()
+} {
+	p, err := Start(options...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return p
+}
+
+// Restart begins a new profiling session with the given options.
+// It is intended for callers who stopped a previous session early via
+// StopSignal and want to spin up another round; Start already allows
+// this once Stop has reset the started flag, but Restart makes that
+// intent explicit at the call site.
+func Restart(options ...func(*Profile)) (interface {
+	Stop()
+}, error) {
+	return Start(options...)
 }
 
 func localDir() string {
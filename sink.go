@@ -0,0 +1,59 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Sink abstracts where a finished profile is written, so profiling is
+// not limited to plain files on the local filesystem. Create is called
+// once per enabled profile mode with its conventional name (e.g.
+// "cpu.pprof"); the returned writer is closed once the profile has been
+// fully written.
+type Sink interface {
+	Create(name string) (io.WriteCloser, error)
+}
+
+// fsSink is the default Sink, installed by Start when no sink has been
+// configured via WithSink. It writes each profile as a plain file
+// rooted at path, matching the package's original on-disk behavior.
+type fsSink struct {
+	path string
+}
+
+func (s *fsSink) Create(name string) (io.WriteCloser, error) {
+	return os.Create(filepath.Join(s.path, name))
+}
+
+// gzipSink wraps another Sink, compressing every profile written
+// through it and appending ".gz" to its name.
+type gzipSink struct {
+	next Sink
+}
+
+func (s *gzipSink) Create(name string) (io.WriteCloser, error) {
+	w, err := s.next.Create(name + ".gz")
+	if err != nil {
+		return nil, err
+	}
+	return &gzipWriteCloser{w: w, gz: gzip.NewWriter(w)}, nil
+}
+
+// gzipWriteCloser gzip-compresses everything written to it before
+// passing it on to the underlying writer, and closes both in order.
+type gzipWriteCloser struct {
+	w  io.WriteCloser
+	gz *gzip.Writer
+}
+
+func (g *gzipWriteCloser) Write(p []byte) (int, error) { return g.gz.Write(p) }
+
+func (g *gzipWriteCloser) Close() error {
+	if err := g.gz.Close(); err != nil {
+		g.w.Close()
+		return err
+	}
+	return g.w.Close()
+}
@@ -0,0 +1,53 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPSink is a Sink that uploads each finished profile to an HTTP
+// endpoint via POST once it has been fully written, for integration
+// with continuous-profiling collectors and Kubernetes sidecars without
+// forking the package. The profile name (e.g. "cpu.pprof") is appended
+// to URL as a path segment.
+type HTTPSink struct {
+	// URL is the base endpoint each profile is posted to.
+	URL string
+
+	// Client is used to perform the upload. If nil, http.DefaultClient
+	// is used.
+	Client *http.Client
+}
+
+// Create implements Sink.
+func (s *HTTPSink) Create(name string) (io.WriteCloser, error) {
+	return &httpWriteCloser{sink: s, name: name}, nil
+}
+
+type httpWriteCloser struct {
+	sink *HTTPSink
+	name string
+	buf  bytes.Buffer
+}
+
+func (w *httpWriteCloser) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *httpWriteCloser) Close() error {
+	client := w.sink.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Post(w.sink.URL+"/"+w.name, "application/octet-stream", &w.buf)
+	if err != nil {
+		return fmt.Errorf("profile: could not upload %s: %v", w.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("profile: upload of %s to %s failed: %s", w.name, w.sink.URL, resp.Status)
+	}
+	return nil
+}
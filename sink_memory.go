@@ -0,0 +1,49 @@
+package profile
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// MemorySink is a Sink that keeps each finished profile in memory
+// instead of writing it to disk, keyed by its name (e.g. "cpu.pprof").
+// It is useful for tests and for pushing profiles over RPC rather than
+// leaving them on the local filesystem. It is safe for concurrent use.
+type MemorySink struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewMemorySink returns an empty MemorySink ready for use with WithSink.
+func NewMemorySink() *MemorySink {
+	return &MemorySink{files: make(map[string][]byte)}
+}
+
+// Create implements Sink.
+func (s *MemorySink) Create(name string) (io.WriteCloser, error) {
+	return &memoryWriteCloser{sink: s, name: name}, nil
+}
+
+// Bytes returns the bytes written for the named profile, or nil if it
+// has not been written yet (or was never enabled).
+func (s *MemorySink) Bytes(name string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.files[name]
+}
+
+type memoryWriteCloser struct {
+	sink *MemorySink
+	name string
+	buf  bytes.Buffer
+}
+
+func (w *memoryWriteCloser) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *memoryWriteCloser) Close() error {
+	w.sink.mu.Lock()
+	w.sink.files[w.name] = w.buf.Bytes()
+	w.sink.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,124 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGzipSinkProducesValidGzipStream(t *testing.T) {
+	mem := NewMemorySink()
+	gz := &gzipSink{next: mem}
+
+	w, err := gz.Create("cpu.pprof")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	want := []byte("some profile bytes")
+	if _, err := w.Write(want); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	compressed := mem.Bytes("cpu.pprof.gz")
+	if compressed == nil {
+		t.Fatal("expected data written under the .gz-suffixed name")
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading decompressed data: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("decompressed = %q, want %q", got, want)
+	}
+}
+
+func TestMemorySinkBytes(t *testing.T) {
+	mem := NewMemorySink()
+
+	if got := mem.Bytes("missing.pprof"); got != nil {
+		t.Errorf("Bytes for unwritten name = %q, want nil", got)
+	}
+
+	w, err := mem.Create("mem.pprof")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	want := []byte("heap profile data")
+	if _, err := w.Write(want); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if got := mem.Bytes("mem.pprof"); got != nil {
+		t.Errorf("Bytes before Close = %q, want nil", got)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := mem.Bytes("mem.pprof"); !bytes.Equal(got, want) {
+		t.Errorf("Bytes after Close = %q, want %q", got, want)
+	}
+}
+
+func TestHTTPSinkUploadsOnClose(t *testing.T) {
+	var gotBody []byte
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotBody, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sink := &HTTPSink{URL: srv.URL}
+	w, err := sink.Create("trace.out")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	want := []byte("trace bytes")
+	if _, err := w.Write(want); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if gotPath != "/trace.out" {
+		t.Errorf("uploaded path = %q, want %q", gotPath, "/trace.out")
+	}
+	if !bytes.Equal(gotBody, want) {
+		t.Errorf("uploaded body = %q, want %q", gotBody, want)
+	}
+}
+
+func TestHTTPSinkSurfacesNonSuccessStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	sink := &HTTPSink{URL: srv.URL}
+	w, err := sink.Create("cpu.pprof")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := w.Write([]byte("data")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if err := w.Close(); err == nil {
+		t.Fatal("Close should return an error for a non-2xx upload response")
+	}
+}
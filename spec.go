@@ -0,0 +1,114 @@
+package profile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// specModes maps the mode names accepted by ParseSpec to the option
+// functions they enable.
+var specModes = map[string]func(*Profile){
+	"cpu":          CPUProfile,
+	"mem":          MemProfile,
+	"mutex":        MutexProfile,
+	"block":        BlockProfile,
+	"trace":        TraceProfile,
+	"goroutine":    GoroutineProfile,
+	"threadcreate": ThreadcreationProfile,
+	"allocs":       AllocsProfile,
+	"all":          ProfileAll,
+}
+
+// ParseSpec parses a semicolon-separated profile specification, such as
+// "mode=cpu,mem,trace;path=/tmp/p;memrate=1024;blockrate=100;duration=30s;quiet;noshutdown",
+// into the equivalent option functions. It lets operators wire profiling
+// through a single env var or CLI flag without recompiling.
+//
+// Recognised keys are "mode" (comma-separated mode names, see specModes),
+// "path", "memrate", "mutexfraction", "blockrate" and "duration"; the bare
+// tokens "quiet" and "noshutdown" map to Quiet and NoShutdownHook.
+func ParseSpec(spec string) ([]func(*Profile), error) {
+	var options []func(*Profile)
+
+	for _, token := range strings.Split(spec, ";") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		parts := strings.SplitN(token, "=", 2)
+		key := parts[0]
+		if len(parts) == 1 {
+			switch key {
+			case "quiet":
+				options = append(options, Quiet)
+			case "noshutdown":
+				options = append(options, NoShutdownHook)
+			default:
+				return nil, fmt.Errorf("profile: unknown spec flag %q", key)
+			}
+			continue
+		}
+		value := parts[1]
+
+		switch key {
+		case "mode":
+			for _, mode := range strings.Split(value, ",") {
+				option, ok := specModes[strings.TrimSpace(mode)]
+				if !ok {
+					return nil, fmt.Errorf("profile: unknown spec mode %q", mode)
+				}
+				options = append(options, option)
+			}
+		case "path":
+			options = append(options, ProfilePath(value))
+		case "memrate":
+			rate, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("profile: invalid spec memrate %q: %v", value, err)
+			}
+			options = append(options, MemProfileRate(rate))
+		case "mutexfraction":
+			fraction, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("profile: invalid spec mutexfraction %q: %v", value, err)
+			}
+			options = append(options, MutexProfileFraction(fraction))
+		case "blockrate":
+			rate, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("profile: invalid spec blockrate %q: %v", value, err)
+			}
+			options = append(options, BlockProfileRate(rate))
+		case "duration":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("profile: invalid spec duration %q: %v", value, err)
+			}
+			options = append(options, Duration(d))
+		default:
+			return nil, fmt.Errorf("profile: unknown spec key %q", key)
+		}
+	}
+
+	return options, nil
+}
+
+// StartFromSpec parses spec with ParseSpec and starts a new profiling
+// session from the resulting options, exactly as Start would, returning
+// an error instead of terminating the host program on a bad spec or
+// setup failure. This is especially useful for container deployments,
+// where wiring a single env var or CLI flag is easier than adding
+// option calls in code; crash-safety there matters just as much as it
+// does for direct Start calls.
+func StartFromSpec(spec string) (interface {
+	Stop()
+}, error) {
+	options, err := ParseSpec(spec)
+	if err != nil {
+		return nil, fmt.Errorf("profile: could not parse spec %q: %v", spec, err)
+	}
+	return Start(options...)
+}
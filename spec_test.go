@@ -0,0 +1,126 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func applySpec(t *testing.T, spec string) *Profile {
+	t.Helper()
+	options, err := ParseSpec(spec)
+	if err != nil {
+		t.Fatalf("ParseSpec(%q) returned unexpected error: %v", spec, err)
+	}
+	var p Profile
+	for _, option := range options {
+		option(&p)
+	}
+	return &p
+}
+
+func TestParseSpecHappyPath(t *testing.T) {
+	spec := "mode=cpu,mem;path=/tmp/p;memrate=1024;blockrate=100;duration=30s;quiet;noshutdown"
+	p := applySpec(t, spec)
+
+	if !p.cpuMode {
+		t.Error("expected cpuMode to be enabled")
+	}
+	if !p.memMode {
+		t.Error("expected memMode to be enabled")
+	}
+	if p.path != "/tmp/p" {
+		t.Errorf("path = %q, want %q", p.path, "/tmp/p")
+	}
+	if p.memProfileRate != 1024 {
+		t.Errorf("memProfileRate = %d, want 1024", p.memProfileRate)
+	}
+	if !p.blockMode || p.blockProfileRate != 100 {
+		t.Errorf("blockMode = %v, blockProfileRate = %d, want true, 100", p.blockMode, p.blockProfileRate)
+	}
+	if p.duration != 30*time.Second {
+		t.Errorf("duration = %v, want 30s", p.duration)
+	}
+
+	if p.logger == nil {
+		t.Fatal("expected the quiet flag to install a logger")
+	}
+	if !p.noShutdownHook {
+		t.Error("expected noShutdownHook to be enabled")
+	}
+}
+
+func TestParseSpecModeCommaSplit(t *testing.T) {
+	p := applySpec(t, "mode=cpu,mem,trace")
+
+	if !p.cpuMode || !p.memMode || !p.traceMode {
+		t.Errorf("cpuMode=%v memMode=%v traceMode=%v, want all true", p.cpuMode, p.memMode, p.traceMode)
+	}
+	if p.mutexMode || p.blockMode || p.goroutineMode || p.threadcreateMode || p.allocsMode {
+		t.Error("expected only the listed modes to be enabled")
+	}
+}
+
+func TestParseSpecMutexFraction(t *testing.T) {
+	p := applySpec(t, "mode=mutex;mutexfraction=5")
+
+	if !p.mutexMode {
+		t.Error("expected mutexMode to be enabled")
+	}
+	if p.mutexProfileFraction != 5 {
+		t.Errorf("mutexProfileFraction = %d, want 5", p.mutexProfileFraction)
+	}
+}
+
+func TestParseSpecAll(t *testing.T) {
+	p := applySpec(t, "mode=all")
+
+	if !p.cpuMode || !p.memMode || !p.mutexMode || !p.blockMode || !p.traceMode ||
+		!p.goroutineMode || !p.threadcreateMode || !p.allocsMode {
+		t.Error("expected mode=all to enable every profile mode")
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"unknown flag", "bogus"},
+		{"unknown key", "bogus=1"},
+		{"unknown mode", "mode=bogus"},
+		{"invalid memrate", "memrate=notanumber"},
+		{"invalid mutexfraction", "mutexfraction=notanumber"},
+		{"invalid blockrate", "blockrate=notanumber"},
+		{"invalid duration", "duration=notaduration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSpec(tt.spec)
+			if err == nil {
+				t.Fatalf("ParseSpec(%q) = nil error, want error", tt.spec)
+			}
+		})
+	}
+}
+
+func TestParseSpecEmptyTokensIgnored(t *testing.T) {
+	options, err := ParseSpec(" ; mode=cpu ; ; ")
+	if err != nil {
+		t.Fatalf("ParseSpec returned unexpected error: %v", err)
+	}
+	if len(options) != 1 {
+		t.Fatalf("got %d options, want 1", len(options))
+	}
+}
+
+func TestStartFromSpecReturnsErrorOnBadSpec(t *testing.T) {
+	_, err := StartFromSpec("mode=bogus")
+	if err == nil {
+		t.Fatal("StartFromSpec with a bad spec should return an error, not start a profile")
+	}
+	if !strings.Contains(err.Error(), "mode") {
+		t.Errorf("error %q does not mention the bad spec", err)
+	}
+}